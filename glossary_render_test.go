@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestRenderGlossaryHTML(t *testing.T) {
+	toks := []glossaryToken{
+		{Text: "the"},
+		{Text: "BUI", IsGlossary: true},
+		{Text: "field"},
+	}
+	got := string(renderGlossaryHTML(toks))
+	want := ` the <span class="glossary"><a href="glossary.html#bui">BUI</a></span> field`
+	if got != want {
+		t.Errorf("renderGlossaryHTML() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderGlossaryMD(t *testing.T) {
+	toks := []glossaryToken{
+		{Text: "the"},
+		{Text: "BUI", IsGlossary: true},
+		{Text: "field"},
+	}
+	got := renderGlossaryMD(toks)
+	want := ` the [BUI](glossary.html#bui) field`
+	if got != want {
+		t.Errorf("renderGlossaryMD() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderGlossaryText(t *testing.T) {
+	toks := []glossaryToken{
+		{Text: "the"},
+		{Text: "BUI", IsGlossary: true},
+		{Text: "field"},
+	}
+	got := renderGlossaryText(toks)
+	want := " the BUI field"
+	if got != want {
+		t.Errorf("renderGlossaryText() = %q, want %q (no markup expected)", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"go/ast"
+)
+
+// funcIsHandler reports whether fn looks like an HTTP handler function,
+// i.e. it takes a http.ResponseWriter and a *http.Request, so that
+// processWebDocLines knows it's worth inferring @input/@response from the
+// body when the author omitted them.
+func funcIsHandler(fn *ast.FuncDecl) bool {
+	if fn.Type.Params == nil {
+		return false
+	}
+	var hasWriter, hasRequest bool
+	for _, field := range fn.Type.Params.List {
+		switch t := field.Type.(type) {
+		case *ast.SelectorExpr:
+			if pkgIdent(t.X) == "http" && t.Sel.Name == "ResponseWriter" {
+				hasWriter = true
+			}
+		case *ast.StarExpr:
+			if sel, ok := t.X.(*ast.SelectorExpr); ok {
+				if pkgIdent(sel.X) == "http" && sel.Sel.Name == "Request" {
+					hasRequest = true
+				}
+			}
+		}
+	}
+	return hasWriter && hasRequest
+}
+
+// pkgIdent returns the identifier name of a (presumed) package-qualifier
+// expression, or "" if expr isn't a plain identifier.
+func pkgIdent(expr ast.Expr) string {
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// inferInputType walks fn's body looking for a call of the form
+// json.Unmarshal(&FooRequest{...}, ...) and returns "FooRequest", so
+// authors can omit @input when the request type is inferable.
+func inferInputType(fn *ast.FuncDecl) string {
+	var found string
+	if fn.Body == nil {
+		return ""
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || pkgIdent(sel.X) != "json" || sel.Sel.Name != "Unmarshal" {
+			return true
+		}
+		if len(call.Args) < 1 {
+			return true
+		}
+		if t := compositeLitTypeName(call.Args[0]); t != "" {
+			found = t
+		}
+		return true
+	})
+	return found
+}
+
+// inferResponseType walks fn's body looking for a call of the form
+// SvcWriteResponse(FooResponse{...}) (with an optional leading http.Request
+// or ResponseWriter argument, as rentroll's handlers pass) and returns
+// "FooResponse".
+func inferResponseType(fn *ast.FuncDecl) string {
+	var found string
+	if fn.Body == nil {
+		return ""
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if found != "" {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		name := ""
+		switch f := call.Fun.(type) {
+		case *ast.Ident:
+			name = f.Name
+		case *ast.SelectorExpr:
+			name = f.Sel.Name
+		}
+		if name != "SvcWriteResponse" {
+			return true
+		}
+		for _, a := range call.Args {
+			if t := compositeLitTypeName(a); t != "" {
+				found = t
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// compositeLitTypeName returns "FooRequest" for expr shaped like
+// &FooRequest{...} or FooRequest{...}, and "" otherwise.
+func compositeLitTypeName(expr ast.Expr) string {
+	if u, ok := expr.(*ast.UnaryExpr); ok {
+		expr = u.X
+	}
+	cl, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return ""
+	}
+	switch t := cl.Type.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
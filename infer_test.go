@@ -0,0 +1,99 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+// parseFunc parses src (a single top-level func decl, plus whatever
+// imports/types it needs) and returns the *ast.FuncDecl named name.
+func parseFunc(t *testing.T, src, name string) *ast.FuncDecl {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", "package p\n"+src, 0)
+	if err != nil {
+		t.Fatalf("parse error: %s", err)
+	}
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Name.Name == name {
+			return fn
+		}
+	}
+	t.Fatalf("func %s not found", name)
+	return nil
+}
+
+func TestFuncIsHandler(t *testing.T) {
+	fn := parseFunc(t, `
+func Handler(w http.ResponseWriter, r *http.Request) {}
+`, "Handler")
+	if !funcIsHandler(fn) {
+		t.Error("expected Handler to be recognized as an HTTP handler")
+	}
+
+	notHandler := parseFunc(t, `
+func NotHandler(a int, b string) {}
+`, "NotHandler")
+	if funcIsHandler(notHandler) {
+		t.Error("expected NotHandler not to be recognized as an HTTP handler")
+	}
+}
+
+func TestInferInputType(t *testing.T) {
+	fn := parseFunc(t, `
+func Handler(w http.ResponseWriter, r *http.Request) {
+	var req FooRequest
+	json.Unmarshal(&FooRequest{}, &req)
+}
+`, "Handler")
+	if got := inferInputType(fn); got != "FooRequest" {
+		t.Errorf("inferInputType() = %q, want %q", got, "FooRequest")
+	}
+}
+
+func TestInferInputTypeNoUnmarshal(t *testing.T) {
+	fn := parseFunc(t, `
+func Handler(w http.ResponseWriter, r *http.Request) {}
+`, "Handler")
+	if got := inferInputType(fn); got != "" {
+		t.Errorf("inferInputType() = %q, want empty", got)
+	}
+}
+
+func TestInferResponseType(t *testing.T) {
+	fn := parseFunc(t, `
+func Handler(w http.ResponseWriter, r *http.Request) {
+	SvcWriteResponse(r, FooResponse{})
+}
+`, "Handler")
+	if got := inferResponseType(fn); got != "FooResponse" {
+		t.Errorf("inferResponseType() = %q, want %q", got, "FooResponse")
+	}
+}
+
+func TestCompositeLitTypeName(t *testing.T) {
+	fn := parseFunc(t, `
+func Handler() {
+	x := &FooRequest{}
+	_ = x
+	y := BarResponse{}
+	_ = y
+}
+`, "Handler")
+	var names []string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok {
+			for _, rhs := range assign.Rhs {
+				if name := compositeLitTypeName(rhs); name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		return true
+	})
+	if len(names) != 2 || names[0] != "FooRequest" || names[1] != "BarResponse" {
+		t.Errorf("compositeLitTypeName() found %v, want [FooRequest BarResponse]", names)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"html/template"
 	"os"
 	"path/filepath"
@@ -49,17 +51,23 @@ type URLDef struct {
 // are set as the comments are parsed. The data in this struct is used to
 // create an html file describing the web service.
 type DirectiveData struct {
-	Title       string         // name of web service
-	URLs        []URLDef       // one or more URLs defining the
-	Synopsis    string         // One line explanation
-	Method      []string       // POST, GET, ...
-	Description template.HTML  // detailed explanation
-	Input       []ProtocolJSON // JSON input data
-	InputEx     template.HTML  // sample JSON
-	Response    []ProtocolJSON // JSON response data
-	ResponseEx  template.HTML  // sample JSON
-	Filename    string         // the name of the html file describing the web service
-	ID          string         // a unique id used in the UI
+	Title           string         // name of web service
+	URLs            []URLDef       // one or more URLs defining the
+	Synopsis        string         // One line explanation
+	Method          []string       // POST, GET, ...
+	Description     template.HTML  // detailed explanation, with HTML glossary-term markup
+	DescriptionMD   string         // detailed explanation, with Markdown glossary-term links, for -format=md
+	DescriptionText string         // detailed explanation, plain text with no glossary markup, for -format=txt
+	Input           []ProtocolJSON // JSON input data
+	InputType       string         // the @input type token, kept to re-resolve Input with different options
+	InputEx         template.HTML  // sample JSON
+	Response        []ProtocolJSON // JSON response data
+	ResponseType    string         // the @response type token, kept to re-resolve Response with different options
+	ResponseEx      template.HTML  // sample JSON
+	Filename        string         // the name of the html file describing the web service
+	ID              string         // a unique id used in the UI
+	SrcFile         string         // the Go source file the wsdoc block was found in
+	SrcLine         int            // the line number of the "wsdoc {" marker in SrcFile
 }
 
 // Directive is a struct describing a particular Cmd within the WS DOC comments
@@ -98,6 +106,20 @@ var App struct {
 	ExecutableDir string
 }
 
+// currentSrcFile and currentSrcLine track the wsdoc block currently being
+// processed. definitionLookup reads them to record where each referenced
+// term came from, so the cross-reference report can say "undefined
+// glossary term at foo.go:123" instead of just the term name.
+var currentSrcFile string
+var currentSrcLine int
+
+// skipStaticWrite suppresses the per-service writeServicePage call in
+// generateHTMLRefPage. It is set before the directive parser runs when the
+// selected backend has no use for the ./doc tree: the -openapi backend
+// writes its own spec file instead, and the live -http server renders
+// DirectiveData from the in-memory Corpus rather than from disk.
+var skipStaticWrite bool
+
 // AnalyzeType determines:
 //		if the field is a slice
 //		if the type requires recursion
@@ -121,15 +143,22 @@ func AnalyzeType(t string) (bool, bool, string) {
 	return IsSlice, Recursion, Tname
 }
 
-// ListVars lists the names of the variables within a struct and their types
-func ListVars(a interface{}, d *Directive, prefix template.HTML) []ProtocolJSON {
+// ListVars lists the names of the variables within a struct and their types.
+// By default only exported fields are listed, matching what actually appears
+// in the JSON wire format; pass includeUnexported to also list unexported
+// fields, for ?m=all requests.
+func ListVars(a interface{}, d *Directive, prefix template.HTML, includeUnexported bool) []ProtocolJSON {
 	var m []ProtocolJSON
 	v := reflect.ValueOf(a).Elem()
 	prefix = "&nbsp;&nbsp;&nbsp;&nbsp;" + prefix
 	for j := 0; j < v.NumField(); j++ {
+		sf := v.Type().Field(j)
+		if sf.PkgPath != "" && !includeUnexported { // unexported field, skip unless asked for
+			continue
+		}
 		var p ProtocolJSON
 		f := v.Field(j)
-		p.Field = prefix + template.HTML(v.Type().Field(j).Name)   // set the field name
+		p.Field = prefix + template.HTML(sf.Name)                  // set the field name
 		p.DataType = template.HTML(f.Type().String())              // set its data type
 		isSlice, recurse, rtype := AnalyzeType(string(p.DataType)) // analyze and modify as needed
 		sl := ""
@@ -137,12 +166,12 @@ func ListVars(a interface{}, d *Directive, prefix template.HTML) []ProtocolJSON
 			sl = "[]"
 		}
 		p.DataType = template.HTML(sl + rtype)
-		p.Definition = getDefinition(string(p.Field))
+		p.Definition = definitionLookup(string(p.Field))
 		// fmt.Printf("Name = %s, Recurse = %t,  Kind = %s,  type = %s\n", p.Field, recurse, f.Kind().String(), rtype)
 		m = append(m, p)
 		if recurse {
 			x := WSTypeFactory[rtype]()
-			n := ListVars(x, d, prefix+template.HTML(rtype+"."))
+			n := ListVars(x, d, prefix+template.HTML(rtype+"."), includeUnexported)
 			m = append(m, n...)
 		}
 	}
@@ -180,7 +209,7 @@ func handleURL(s string, d *Directive) {
 		if strings.Contains(sa[i], ":") { // are there any parts that need definitions?
 			var t URLTerm
 			t.Term = rlib.Stripchars(sa[i], ":")
-			t.Definition = getDefinition(strings.ToLower(t.Term))
+			t.Definition = definitionLookup(strings.ToLower(t.Term))
 			u.Parts = append(u.Parts, t) // yes: add it to the list, remove the colon
 		}
 	}
@@ -198,7 +227,7 @@ func handleURL(s string, d *Directive) {
 				if len(sc) > 1 && strings.Contains(sc[1], ":") {
 					var t URLTerm
 					t.Term = rlib.Stripchars(sc[1], ":")
-					t.Definition = getDefinition(t.Term)
+					t.Definition = definitionLookup(t.Term)
 					u.Parts = append(u.Parts, t) // yes: add it to the list, remove the colon
 				}
 			}
@@ -217,8 +246,21 @@ func handleSynopsis(s string, d *Directive) {
 	d.D.Synopsis = strings.TrimSpace(s[len(d.Cmd):])
 }
 
-func handleGlossaryTerms(src string) template.HTML {
-	var s2 template.HTML
+// glossaryToken is one word of a tokenized description, annotated with
+// whether it's a recognized glossary term. Tokenizing once and rendering
+// per format keeps the glossary markup out of the plain-text/Markdown
+// output instead of baking HTML into Description and hoping downstream
+// renderers strip it back out.
+type glossaryToken struct {
+	Text       string
+	IsGlossary bool
+}
+
+// tokenizeGlossaryTerms scans src for colon-prefixed glossary references
+// (":term") and returns every word, flagging the ones that are recognized
+// glossary terms.
+func tokenizeGlossaryTerms(src string) []glossaryToken {
+	var toks []glossaryToken
 	var s scanner.Scanner
 	s.Filename = "sample"
 	s.Init(strings.NewReader(src))
@@ -228,29 +270,68 @@ func handleGlossaryTerms(src string) template.HTML {
 		if s.TokenText() == ":" {
 			tok = s.Scan()
 			if tok != scanner.EOF {
-				if IsGlossaryTerm(s.TokenText()) {
-					s2 += template.HTML(" <span class=\"glossary\">" + s.TokenText() + "</span>")
-				} else {
-					s2 += template.HTML(" " + s.TokenText())
-				}
+				term := s.TokenText()
+				toks = append(toks, glossaryToken{Text: term, IsGlossary: IsGlossaryTerm(term)})
 			} else {
-				s2 += template.HTML(":")
+				toks = append(toks, glossaryToken{Text: ":"})
 			}
 		} else {
-			s2 += template.HTML(" " + s.TokenText())
+			toks = append(toks, glossaryToken{Text: s.TokenText()})
+		}
+	}
+	return toks
+}
+
+// renderGlossaryHTML renders toks for the HTML renderer: glossary terms
+// become <span class="glossary"> links to glossary.html#term.
+func renderGlossaryHTML(toks []glossaryToken) template.HTML {
+	var s2 template.HTML
+	for _, t := range toks {
+		if t.IsGlossary {
+			s2 += template.HTML(" <span class=\"glossary\"><a href=\"glossary.html#" + strings.ToLower(t.Text) + "\">" + t.Text + "</a></span>")
+		} else {
+			s2 += template.HTML(" " + t.Text)
 		}
 	}
 	return s2
 }
 
+// renderGlossaryMD renders toks for the Markdown renderer: glossary terms
+// become `[term](glossary.html#term)` links.
+func renderGlossaryMD(toks []glossaryToken) string {
+	var sb strings.Builder
+	for _, t := range toks {
+		if t.IsGlossary {
+			sb.WriteString(" [" + t.Text + "](glossary.html#" + strings.ToLower(t.Text) + ")")
+		} else {
+			sb.WriteString(" " + t.Text)
+		}
+	}
+	return sb.String()
+}
+
+// renderGlossaryText renders toks for the plain-text renderer: no markup
+// at all, just the words themselves.
+func renderGlossaryText(toks []glossaryToken) string {
+	var sb strings.Builder
+	for _, t := range toks {
+		sb.WriteString(" " + t.Text)
+	}
+	return sb.String()
+}
+
 func handleDescription(s string, d *Directive) {
 	s1 := strings.TrimSpace(s[len(d.Cmd):])
-	// look for any words that indicate it need to be surrounded with <code> tags.
-	s2 := handleGlossaryTerms(s1)
+	toks := tokenizeGlossaryTerms(s1)
+	html, md, txt := renderGlossaryHTML(toks), renderGlossaryMD(toks), renderGlossaryText(toks)
 	if len(d.D.Description) == 0 {
-		d.D.Description = s2
+		d.D.Description = html
+		d.D.DescriptionMD = md
+		d.D.DescriptionText = txt
 	} else {
-		d.D.Description += " " + s2
+		d.D.Description += " " + html
+		d.D.DescriptionMD += " " + md
+		d.D.DescriptionText += " " + txt
 	}
 }
 
@@ -266,17 +347,22 @@ func handleMethod(s string, d *Directive) {
 
 func handleInput(s string, d *Directive) {
 	s1 := strings.TrimSpace(s[len(d.Cmd):])
-	d.D.Input = getStructDef(s1, d)
+	d.D.InputType = s1
+	d.D.Input = getStructDef(s1, d, false)
 	d.D.InputEx = GenExample(s1)
 }
 
 func handleResponse(s string, d *Directive) {
 	s1 := strings.TrimSpace(s[len(d.Cmd):])
-	d.D.Response = getStructDef(s1, d)
+	d.D.ResponseType = s1
+	d.D.Response = getStructDef(s1, d, false)
 	d.D.ResponseEx = GenExample(s1)
 }
 
-func getStructDef(s string, d *Directive) []ProtocolJSON {
+// getStructDef resolves s (a type token, or the literal "string") to its
+// field list via WSTypeFactory/ListVars. includeUnexported is forwarded to
+// ListVars for ?m=all / "all fields" requests.
+func getStructDef(s string, d *Directive, includeUnexported bool) []ProtocolJSON {
 	ss := strings.Split(s, " ")
 	for i := 0; i < len(ss); i++ {
 		t := strings.TrimSpace(ss[i])
@@ -286,7 +372,7 @@ func getStructDef(s string, d *Directive) []ProtocolJSON {
 		_, ok := WSTypeFactory[t]
 		if ok {
 			x := WSTypeFactory[t]()
-			return ListVars(x, d, template.HTML(""))
+			return ListVars(x, d, template.HTML(""), includeUnexported)
 		}
 		if strings.ToLower(t) == "string" {
 			var p ProtocolJSON
@@ -300,49 +386,41 @@ func getStructDef(s string, d *Directive) []ProtocolJSON {
 	return []ProtocolJSON{}
 }
 
+// generateHTMLRefPage renders d's documentation page through the Renderer
+// selected by -format (HTML by default), writing into ./doc. It is a no-op
+// when skipStaticWrite is set, since the -openapi and -http backends have
+// no use for the per-service files.
 func generateHTMLRefPage(d *DirectiveData) error {
-	path := "./doc"
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		os.Mkdir(path, os.ModeDir|0777)
-	}
-	f, err := os.Create(path + "/" + d.Filename)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	t, err := template.New("doc.html").ParseFiles(App.ExecutableDir + "/doc.html")
-	if nil != err {
-		fmt.Printf("Error loading template: %v\n", err)
-	}
-	if err = t.Execute(f, d); err != nil {
-		fmt.Printf("Error executing template: %v\n", err)
+	if skipStaticWrite {
+		return nil
 	}
-	return err
+	return writeServicePage(d)
 }
 
 // processWebDocLines builds the documentation for a single web service call. The content
-// defining the document is contained in the supplied array of strings.  In particular,
-// the definitions it looks for are:
+// defining the document is contained in the supplied array of strings (the text of each
+// comment line within a "wsdoc { ... wsdoc }" block, with the leading "//" stripped).
+// In particular, the definitions it looks for are:
 //		@URL		 - the format of the url
 //		@Synopsis	 - 1 sentence description
 //		@Description - detailed explanation of the web service
 //		@Input		 - format and content of data required as input
 //		@Response	 - format and content of data returned -- if it is a Go data type it
 //						will be expanded
-func processWebDocLines(sa []string) {
+// fn is the *ast.FuncDecl the wsdoc block was attached to, used to auto-discover
+// @input/@response when the author omitted them; it is nil when that isn't available.
+func processWebDocLines(sa []string, srcFile string, srcLine int, fn *ast.FuncDecl) {
 	if len(sa) == 0 {
 		return
 	}
 	var d DirectiveData
+	d.SrcFile = srcFile
+	d.SrcLine = srcLine
+	currentSrcFile, currentSrcLine = srcFile, srcLine
 	for i := 0; i < len(sa); i++ {
-		ss := strings.Split(sa[i], "//")
-		if len(ss) < 2 {
-			continue
-		}
+		s := strings.TrimSpace(sa[i])
+		sl := strings.ToLower(s)
 		for j := 0; j < len(Directives); j++ {
-			s := strings.TrimSpace(ss[1])
-			sl := strings.ToLower(s)
 			if strings.Index(sl, Directives[j].Cmd) == 0 {
 				Directives[j].D = &d
 				Directives[j].Handler(s, &Directives[j])
@@ -350,6 +428,23 @@ func processWebDocLines(sa []string) {
 			}
 		}
 	}
+
+	if fn != nil && funcIsHandler(fn) {
+		tmp := &Directive{D: &d}
+		if len(d.Input) == 0 {
+			if t := inferInputType(fn); t != "" {
+				d.InputType = t
+				d.Input = getStructDef(t, tmp, false)
+			}
+		}
+		if len(d.Response) == 0 {
+			if t := inferResponseType(fn); t != "" {
+				d.ResponseType = t
+				d.Response = getStructDef(t, tmp, false)
+			}
+		}
+	}
+
 	if err := generateHTMLRefPage(&d); err != nil {
 		fmt.Printf("Error generating reference page: %s\n", err.Error())
 	}
@@ -374,66 +469,85 @@ func (a DirDataSlice) Len() int           { return len(a) }
 func (a DirDataSlice) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a DirDataSlice) Less(i, j int) bool { return a[i].Title < a[j].Title }
 
-// generateDocIndexPage generates the index page for the documentation
+// generateDocIndexPage generates the index page for the documentation,
+// through the Renderer selected by -format (HTML by default).
 func generateDocIndexPage() error {
-	f, err := os.Create("./doc/docs.html")
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
 	sort.Sort(IndexData.TOC)
-	IndexData.Date = time.Now().Format("Jan 2, 2006  3:04PM MST")
-	IndexData.Version = "1.0"
-	t, err := template.New("docs.html").ParseFiles(App.ExecutableDir + "/docs.html")
-	if nil != err {
-		fmt.Printf("Error loading template: %v\n", err)
-	}
-	if err = t.Execute(f, &IndexData); err != nil {
-		fmt.Printf("Error executing template: %v\n", err)
-	}
+	IndexData.Date, IndexData.Version = currentDateVersion()
+	idx := &IndexDataT{TOC: IndexData.TOC, Date: IndexData.Date, Version: IndexData.Version}
+	return writeIndexPage(idx)
+}
 
-	return err
+// currentDateVersion returns the generation date and version string shown
+// on every index page, shared by the static doc index and the live
+// server's /pkg/ and /index pages so they don't drift out of sync.
+func currentDateVersion() (string, string) {
+	return time.Now().Format("Jan 2, 2006  3:04PM MST"), "1.0"
 }
 
-// processGoFiles searches for go files, exclude go unit test files
-// It then opens the file and scans for comment lines containing markers
-// for Web Services Docs.  The markers surrounding these lines are:
-//  	wsdoc {
-//      wsdoc }
-// All lines between these two markers are sent for further processing.
+// processGoFiles searches for go files, excluding only go unit test files.
+// Every remaining file is parsed with go/parser regardless of its
+// //go:build constraints, so platform-specific handlers (e.g. _windows.go,
+// //go:build linux) still get documented when wsdoc itself is run on a
+// different platform - go/parser parses syntax and doesn't evaluate build
+// tags, so there's nothing to filter on here. Every *ast.FuncDecl whose
+// Doc comment contains a "wsdoc { ... wsdoc }" block is sent for further
+// processing, alongside the FuncDecl itself so @input/@response can be
+// inferred when omitted.
 func processGoFiles(path string, f os.FileInfo, err error) error {
+	if err != nil {
+		return err
+	}
 	if f.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(f.Name(), "_test.go") {
 		return nil
 	}
-	// fmt.Printf("processGoFiles:  %s\n", path)
-	file, err := os.Open(path)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
 	if err != nil {
-		return err
+		fmt.Printf("Error parsing %s: %s\n", path, err.Error())
+		return nil
 	}
-	defer file.Close()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	found := false
-	for scanner.Scan() {
-		s := scanner.Text()
-		if !found { // search for start of web service doc
-			found = isCommentContaining(s, "wsdoc {") // look for start of ws docs
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Doc == nil {
 			continue
 		}
-		if isCommentContaining(s, "wsdoc }") { // look for end of ws docs
-			processWebDocLines(lines) // transform into document
-			found = false
-			lines = []string{}
+		lines, startLine, ok := extractWsdocBlock(fn.Doc, fset)
+		if !ok {
 			continue
 		}
-		lines = append(lines, s) // save all lines between start and end of ws docs
+		processWebDocLines(lines, path, startLine, fn)
 	}
-	if scanner.Err() != nil {
-		fmt.Printf("Error scanning file: %s\n", scanner.Err().Error())
+	return nil
+}
+
+// extractWsdocBlock scans the comments in doc for a "wsdoc { ... wsdoc }"
+// block and, if found, returns the lines (comment text with the leading
+// "//" stripped) between the markers, along with the source line of the
+// "wsdoc {" marker itself.
+func extractWsdocBlock(doc *ast.CommentGroup, fset *token.FileSet) ([]string, int, bool) {
+	var lines []string
+	found := false
+	startLine := 0
+	for _, c := range doc.List {
+		if !found {
+			if isCommentContaining(c.Text, "wsdoc {") {
+				found = true
+				startLine = fset.Position(c.Pos()).Line
+			}
+			continue
+		}
+		if isCommentContaining(c.Text, "wsdoc }") {
+			return lines, startLine, true
+		}
+		ss := strings.SplitN(c.Text, "//", 2)
+		if len(ss) == 2 {
+			lines = append(lines, ss[1])
+		}
 	}
-	return scanner.Err()
+	return nil, 0, false
 }
 
 func main() {
@@ -445,17 +559,58 @@ func main() {
 	}
 	var files = []string{"rrglossary", "rrsuppl"}
 	for i := 0; i < len(files); i++ {
-		if err := LoadGlossary(fmt.Sprintf("%s/%s.csv", App.ExecutableDir, files[i])); err != nil {
+		path := fmt.Sprintf("%s/%s.csv", App.ExecutableDir, files[i])
+		if err := LoadGlossary(path); err != nil {
 			fmt.Printf("Error loading %s.csv:  %s\n", files[i], err.Error())
 		}
+		if err := registerGlossaryTermsFromCSV(path); err != nil {
+			fmt.Printf("Error registering glossary terms from %s.csv:  %s\n", files[i], err.Error())
+		}
 	}
 	root := "."
 	flag.Parse()
 	if flag.NArg() > 0 {
 		root = flag.Arg(0)
 	}
+
+	if *httpAddr != "" {
+		if err := runServer(*httpAddr, root); err != nil {
+			fmt.Printf("Error serving: %s\n", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	skipStaticWrite = *openapiFlag
+
 	if err := filepath.Walk(root, processGoFiles); err != nil {
 		fmt.Printf("Error walking file path = %s]\n", err)
 	}
-	generateDocIndexPage()
+
+	if *openapiFlag {
+		_, IndexData.Version = currentDateVersion()
+		if err := generateOpenAPI(); err != nil {
+			fmt.Printf("Error generating OpenAPI spec: %s\n", err.Error())
+			os.Exit(1)
+		}
+	} else {
+		generateDocIndexPage()
+
+		if err := generateGlossaryPage(); err != nil {
+			fmt.Printf("Error generating glossary page: %s\n", err.Error())
+		}
+	}
+
+	// Run the cross-reference/completeness report - and honor -strict -
+	// for every backend, not just the HTML one, so "-openapi -strict"
+	// still catches undefined terms and duplicate titles.
+	rep, err := generateXrefReport(IndexData.TOC)
+	if err != nil {
+		fmt.Printf("Error generating cross-reference report: %s\n", err.Error())
+		os.Exit(1)
+	}
+	printReportSummary(rep)
+	if *strictFlag && !reportIsClean(rep) {
+		os.Exit(1)
+	}
 }
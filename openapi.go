@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// openapiFlag selects the OpenAPI backend instead of the default HTML
+// output. When set, wsdoc writes openapi.yaml and openapi.json in place of
+// the ./doc tree.
+var openapiFlag = flag.Bool("openapi", false, "emit an OpenAPI 3.1 spec (openapi.yaml / openapi.json) instead of HTML")
+
+// serveSwaggerFlag mounts Swagger UI over the generated spec when running
+// in -http server mode.
+var serveSwaggerFlag = flag.Bool("serve-swagger", false, "mount Swagger UI at /swagger/ over the generated OpenAPI spec (requires -http)")
+
+// colonParam matches a colon-prefixed path segment such as :BUI or :RAID.
+var colonParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// OASDocument is the root of an OpenAPI 3.1 document. Only the subset of
+// the spec that wsdoc can populate from DirectiveData is represented here.
+type OASDocument struct {
+	OpenAPI    string                 `yaml:"openapi" json:"openapi"`
+	Info       OASInfo                `yaml:"info" json:"info"`
+	Paths      map[string]OASPathItem `yaml:"paths" json:"paths"`
+	Components OASComponents          `yaml:"components" json:"components"`
+}
+
+// OASInfo is the OpenAPI "info" object.
+type OASInfo struct {
+	Title   string `yaml:"title" json:"title"`
+	Version string `yaml:"version" json:"version"`
+}
+
+// OASPathItem holds one operation per HTTP method for a single path.
+type OASPathItem map[string]*OASOperation
+
+// OASOperation describes a single @method entry under a @url.
+type OASOperation struct {
+	Summary     string                 `yaml:"summary,omitempty" json:"summary,omitempty"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Parameters  []OASParameter         `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	RequestBody *OASRequestBody        `yaml:"requestBody,omitempty" json:"requestBody,omitempty"`
+	Responses   map[string]OASResponse `yaml:"responses" json:"responses"`
+}
+
+// OASParameter describes one path or query parameter, built from a
+// colon-prefixed URL term.
+type OASParameter struct {
+	Name        string     `yaml:"name" json:"name"`
+	In          string     `yaml:"in" json:"in"`
+	Required    bool       `yaml:"required" json:"required"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Schema      *OASSchema `yaml:"schema,omitempty" json:"schema,omitempty"`
+}
+
+// OASRequestBody wraps the @input schema.
+type OASRequestBody struct {
+	Content map[string]OASMediaType `yaml:"content" json:"content"`
+}
+
+// OASResponse wraps the @response schema under the 200 status.
+type OASResponse struct {
+	Description string                  `yaml:"description" json:"description"`
+	Content     map[string]OASMediaType `yaml:"content,omitempty" json:"content,omitempty"`
+}
+
+// OASMediaType is the "application/json" entry of a request or response body.
+type OASMediaType struct {
+	Schema *OASSchema `yaml:"schema" json:"schema"`
+}
+
+// OASComponents holds the shared component.schemas map that
+// protocolToSchema and oasRegisterStruct populate as they recurse through
+// WSTypeFactory.
+type OASComponents struct {
+	Schemas map[string]*OASSchema `yaml:"schemas" json:"schemas"`
+}
+
+// OASSchema is a (simplified) JSON Schema node: either a $ref, a scalar
+// type, or an object/array built up from ProtocolJSON fields.
+type OASSchema struct {
+	Ref         string                `yaml:"$ref,omitempty" json:"$ref,omitempty"`
+	Type        string                `yaml:"type,omitempty" json:"type,omitempty"`
+	Description string                `yaml:"description,omitempty" json:"description,omitempty"`
+	Items       *OASSchema            `yaml:"items,omitempty" json:"items,omitempty"`
+	Properties  map[string]*OASSchema `yaml:"properties,omitempty" json:"properties,omitempty"`
+}
+
+// buildOpenAPI walks toc and produces an OASDocument, populating
+// Components.Schemas for every Go struct reachable through WSTypeFactory.
+// version is the API version string to report in info.version (callers
+// pass whatever they use for the doc index's own Version field, so the
+// two stay in sync).
+func buildOpenAPI(toc DirDataSlice, version string) *OASDocument {
+	doc := &OASDocument{
+		OpenAPI: "3.1.0",
+		Info:    OASInfo{Title: "wsdoc generated API", Version: version},
+		Paths:   make(map[string]OASPathItem),
+		Components: OASComponents{
+			Schemas: make(map[string]*OASSchema),
+		},
+	}
+	for i := range toc {
+		d := &toc[i]
+		for _, u := range d.URLs {
+			addPath(doc, d, u)
+		}
+	}
+	return doc
+}
+
+// addPath converts one @url entry (plus the enclosing DirectiveData) into
+// an OASPathItem with one operation per @method.
+func addPath(doc *OASDocument, d *DirectiveData, u URLDef) {
+	path, params := oasPathAndParams(u)
+	item, ok := doc.Paths[path]
+	if !ok {
+		item = OASPathItem{}
+		doc.Paths[path] = item
+	}
+
+	op := &OASOperation{
+		Summary:     d.Synopsis,
+		Description: d.DescriptionMD,
+		Parameters:  params,
+		Responses:   map[string]OASResponse{},
+	}
+	if len(d.Input) > 0 {
+		op.RequestBody = &OASRequestBody{
+			Content: map[string]OASMediaType{
+				"application/json": {Schema: protocolToSchema(doc, d.ID+"Input", d.Input)},
+			},
+		}
+	}
+	if len(d.Response) > 0 {
+		op.Responses["200"] = OASResponse{
+			Description: "OK",
+			Content: map[string]OASMediaType{
+				"application/json": {Schema: protocolToSchema(doc, d.ID+"Response", d.Response)},
+			},
+		}
+	} else {
+		op.Responses["200"] = OASResponse{Description: "OK"}
+	}
+
+	methods := d.Method
+	if len(methods) == 0 {
+		methods = []string{"GET"}
+	}
+	for _, m := range methods {
+		item[strings.ToLower(m)] = op
+	}
+}
+
+// oasPathAndParams converts a wsdoc URL (":BUI"/":RAID" path segments, plus
+// "?"-separated query params) into an OpenAPI "{BUI}"/"{RAID}" path
+// template and its corresponding parameter list.
+func oasPathAndParams(u URLDef) (string, []OASParameter) {
+	parts := strings.SplitN(u.URL, "?", 2)
+	segs := strings.Split(parts[0], "/")
+	for i, s := range segs {
+		if colonParam.MatchString(s) {
+			segs[i] = colonParam.ReplaceAllString(s, "{$1}")
+		}
+	}
+	path := strings.Join(segs, "/")
+
+	var params []OASParameter
+	seen := make(map[string]bool)
+	for _, t := range u.Parts {
+		if seen[t.Term] {
+			continue
+		}
+		seen[t.Term] = true
+		in := "query"
+		if strings.Contains(path, "{"+t.Term+"}") {
+			in = "path"
+		}
+		params = append(params, OASParameter{
+			Name:        t.Term,
+			In:          in,
+			Required:    in == "path",
+			Description: string(t.Definition),
+			Schema:      &OASSchema{Type: "string"},
+		})
+	}
+	return path, params
+}
+
+// protocolToSchema turns a flattened ProtocolJSON list (as produced by
+// ListVars/getStructDef) into a $ref'd object schema, registering the
+// object (and any nested structs it recurses through) under
+// Components.Schemas.
+func protocolToSchema(doc *OASDocument, name string, fields []ProtocolJSON) *OASSchema {
+	schema := &OASSchema{Type: "object", Properties: map[string]*OASSchema{}}
+	for _, f := range fields {
+		fieldName := strings.TrimSpace(strings.ReplaceAll(string(f.Field), "&nbsp;", ""))
+		if strings.Contains(fieldName, ".") {
+			// Nested fields emitted by ListVars's recursion are folded
+			// into the parent object rather than duplicated as siblings.
+			continue
+		}
+		isSlice, recurse, rtype := AnalyzeType(string(f.DataType))
+		var fs *OASSchema
+		if recurse {
+			ref := oasRegisterStruct(doc, rtype)
+			fs = &OASSchema{Ref: ref}
+		} else {
+			fs = &OASSchema{Type: jsonSchemaType(rtype)}
+		}
+		if isSlice {
+			fs = &OASSchema{Type: "array", Items: fs}
+		}
+		fs.Description = string(f.Definition)
+		schema.Properties[fieldName] = fs
+	}
+	doc.Components.Schemas[name] = schema
+	return &OASSchema{Ref: "#/components/schemas/" + name}
+}
+
+// oasRegisterStruct ensures rtype has a schema registered under
+// Components.Schemas (recursing through WSTypeFactory/ListVars as needed)
+// and returns its $ref path.
+func oasRegisterStruct(doc *OASDocument, rtype string) string {
+	if _, ok := doc.Components.Schemas[rtype]; !ok {
+		if creator, ok := WSTypeFactory[rtype]; ok {
+			doc.Components.Schemas[rtype] = &OASSchema{Type: "object"} // placeholder breaks recursion cycles
+			fields := ListVars(creator(), &Directive{}, "", false)
+			protocolToSchema(doc, rtype, fields)
+		}
+	}
+	return "#/components/schemas/" + rtype
+}
+
+// jsonSchemaType maps a Go scalar type name to its JSON Schema "type".
+func jsonSchemaType(goType string) string {
+	switch strings.ToLower(goType) {
+	case "int", "int32", "int64", "uint", "uint32", "uint64":
+		return "integer"
+	case "float32", "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// generateOpenAPI writes openapi.yaml and openapi.json for the parsed TOC.
+func generateOpenAPI() error {
+	doc := buildOpenAPI(IndexData.TOC, IndexData.Version)
+
+	y, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile("openapi.yaml", y, 0644); err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile("openapi.json", j, 0644)
+}
+
+// swaggerUIHandler serves a minimal Swagger UI page pointed at
+// /openapi.json, for use with -serve-swagger.
+func swaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, `<!DOCTYPE html>
+<html>
+<head><title>wsdoc API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist/swagger-ui-bundle.js"></script>
+<script>
+window.onload = function() {
+  SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+};
+</script>
+</body>
+</html>`)
+}
+
+// registerSwaggerHandlers mounts /swagger/ and /openapi.json on mux,
+// serving the spec built from the corpus's current TOC.
+func (c *Corpus) registerSwaggerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/swagger/", swaggerUIHandler)
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		doc := buildOpenAPI(c.toc, c.version)
+		c.mu.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+	})
+}
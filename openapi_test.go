@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOasPathAndParamsPathParam(t *testing.T) {
+	u := URLDef{
+		URL: "/v1/rentable/:RAID",
+		Parts: []URLTerm{
+			{Term: "RAID", Definition: "the rentable ID"},
+		},
+	}
+	path, params := oasPathAndParams(u)
+	if path != "/v1/rentable/{RAID}" {
+		t.Errorf("path = %q, want %q", path, "/v1/rentable/{RAID}")
+	}
+	if len(params) != 1 {
+		t.Fatalf("got %d params, want 1", len(params))
+	}
+	if params[0].Name != "RAID" || params[0].In != "path" || !params[0].Required {
+		t.Errorf("unexpected param: %+v", params[0])
+	}
+}
+
+func TestOasPathAndParamsQueryParam(t *testing.T) {
+	u := URLDef{
+		URL: "/v1/rentable?BUI",
+		Parts: []URLTerm{
+			{Term: "BUI", Definition: "the business unit ID"},
+		},
+	}
+	path, params := oasPathAndParams(u)
+	if path != "/v1/rentable" {
+		t.Errorf("path = %q, want %q", path, "/v1/rentable")
+	}
+	if len(params) != 1 {
+		t.Fatalf("got %d params, want 1", len(params))
+	}
+	if params[0].Name != "BUI" || params[0].In != "query" || params[0].Required {
+		t.Errorf("unexpected param: %+v", params[0])
+	}
+}
+
+func TestOasPathAndParamsDedup(t *testing.T) {
+	u := URLDef{
+		URL: "/v1/rentable/:BUI",
+		Parts: []URLTerm{
+			{Term: "BUI", Definition: "first"},
+			{Term: "BUI", Definition: "duplicate"},
+		},
+	}
+	_, params := oasPathAndParams(u)
+	if len(params) != 1 {
+		t.Errorf("got %d params, want 1 (duplicate term should be deduped)", len(params))
+	}
+}
+
+func TestAddPathUsesMarkdownDescription(t *testing.T) {
+	doc := &OASDocument{
+		Paths: make(map[string]OASPathItem),
+		Components: OASComponents{
+			Schemas: make(map[string]*OASSchema),
+		},
+	}
+	d := &DirectiveData{
+		ID:              "getfoo",
+		Synopsis:        "get foo",
+		Description:     `the <span class="glossary"><a href="glossary.html#bui">BUI</a></span> field`,
+		DescriptionMD:   "the [BUI](glossary.html#bui) field",
+		DescriptionText: "the BUI field",
+		Method:          []string{"GET"},
+	}
+	u := URLDef{URL: "/v1/foo/:BUI", Parts: []URLTerm{{Term: "BUI", Definition: "the business unit ID"}}}
+
+	addPath(doc, d, u)
+
+	op := doc.Paths["/v1/foo/{BUI}"]["get"]
+	if op.Description != d.DescriptionMD {
+		t.Errorf("Description = %q, want DescriptionMD %q", op.Description, d.DescriptionMD)
+	}
+	if strings.Contains(op.Description, "<span") {
+		t.Error("Description contains raw glossary HTML markup")
+	}
+}
+
+func TestJsonSchemaType(t *testing.T) {
+	cases := map[string]string{
+		"int":     "integer",
+		"int64":   "integer",
+		"float64": "number",
+		"bool":    "boolean",
+		"string":  "string",
+		"Foo":     "string",
+	}
+	for in, want := range cases {
+		if got := jsonSchemaType(in); got != want {
+			t.Errorf("jsonSchemaType(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
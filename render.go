@@ -0,0 +1,219 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	texttemplate "text/template"
+)
+
+// templatesDir, when set, points at a directory of doc.html/docs.html (or
+// doc.md/docs.md, doc.txt/docs.txt) templates that override the defaults
+// (the HTML templates shipped next to the binary, and the built-in Markdown
+// and text templates), mirroring godoc's -templates flag.
+var templatesDir = flag.String("templates", "", "load page templates from this directory instead of the defaults")
+
+// formatFlag selects which Renderer backend writes the doc tree.
+var formatFlag = flag.String("format", "html", "output format: html, md, or txt")
+
+// Renderer is implemented once per output format. RenderService writes a
+// single web service's documentation; RenderIndex writes the
+// table-of-contents page.
+type Renderer interface {
+	RenderService(d *DirectiveData, w io.Writer) error
+	RenderIndex(idx *IndexDataT, w io.Writer) error
+}
+
+// IndexDataT mirrors the anonymous struct type of the package-level
+// IndexData variable so Renderer implementations have something concrete
+// to take a pointer to.
+type IndexDataT struct {
+	TOC     DirDataSlice
+	Date    string
+	Version string
+}
+
+// renderers maps the -format flag value to its Renderer implementation.
+// Registered at init time so new formats are a one-line addition.
+var renderers = map[string]Renderer{
+	"html": htmlRenderer{},
+	"md":   mdRenderer{},
+	"txt":  txtRenderer{},
+}
+
+// renderExt maps a format name to the file extension its output uses.
+var renderExt = map[string]string{
+	"html": ".html",
+	"md":   ".md",
+	"txt":  ".txt",
+}
+
+// currentRenderer looks up the Renderer for -format, defaulting to HTML
+// for an unrecognized value.
+func currentRenderer() Renderer {
+	if r, ok := renderers[*formatFlag]; ok {
+		return r
+	}
+	fmt.Printf("Unknown -format %q, defaulting to html\n", *formatFlag)
+	return renderers["html"]
+}
+
+// templatePath resolves name (e.g. "doc.html") against -templates first,
+// falling back to the directory the binary was loaded from.
+func templatePath(name string) string {
+	if *templatesDir != "" {
+		p := filepath.Join(*templatesDir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return filepath.Join(App.ExecutableDir, name)
+}
+
+// htmlRenderer is the original behavior, now expressed as a Renderer.
+type htmlRenderer struct{}
+
+func (htmlRenderer) RenderService(d *DirectiveData, w io.Writer) error {
+	t, err := template.New("doc.html").ParseFiles(templatePath("doc.html"))
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, d)
+}
+
+func (htmlRenderer) RenderIndex(idx *IndexDataT, w io.Writer) error {
+	t, err := template.New("docs.html").ParseFiles(templatePath("docs.html"))
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, idx)
+}
+
+// mdRenderer writes one Markdown file per service plus an INDEX.md, for
+// publishing wsdoc output to static-site generators or CI artifacts.
+type mdRenderer struct{}
+
+const mdServiceTemplate = `# {{.Title}}
+
+{{.Synopsis}}
+
+{{range .URLs}}` + "`{{.URL}}`" + `
+{{range .Parts}}- ` + "`{{.Term}}`" + `: {{.Definition}}
+{{end}}{{end}}
+## Description
+
+{{.DescriptionMD}}
+`
+
+const mdIndexTemplate = `# API Index
+
+Generated {{.Date}} (v{{.Version}})
+
+{{range .TOC}}- [{{.Title}}]({{.ID}}.md) - {{.Synopsis}}
+{{end}}`
+
+func (mdRenderer) RenderService(d *DirectiveData, w io.Writer) error {
+	t, err := loadTextTemplate("doc.md", mdServiceTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, d)
+}
+
+func (mdRenderer) RenderIndex(idx *IndexDataT, w io.Writer) error {
+	t, err := loadTextTemplate("docs.md", mdIndexTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, idx)
+}
+
+// txtRenderer writes plain-text documentation, for contexts where even
+// Markdown is too much markup.
+type txtRenderer struct{}
+
+const txtServiceTemplate = `{{.Title}}
+{{.Synopsis}}
+
+{{range .URLs}}{{.URL}}
+{{range .Parts}}  {{.Term}}: {{.Definition}}
+{{end}}{{end}}
+{{.DescriptionText}}
+`
+
+const txtIndexTemplate = `API Index ({{.Date}}, v{{.Version}})
+
+{{range .TOC}}{{.Title}} - {{.Synopsis}}
+{{end}}`
+
+func (txtRenderer) RenderService(d *DirectiveData, w io.Writer) error {
+	t, err := loadTextTemplate("doc.txt", txtServiceTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, d)
+}
+
+func (txtRenderer) RenderIndex(idx *IndexDataT, w io.Writer) error {
+	t, err := loadTextTemplate("docs.txt", txtIndexTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, idx)
+}
+
+// loadTextTemplate loads name from -templates if present, otherwise
+// parses the embedded fallback string.
+func loadTextTemplate(name, fallback string) (*texttemplate.Template, error) {
+	if *templatesDir != "" {
+		p := filepath.Join(*templatesDir, name)
+		if _, err := os.Stat(p); err == nil {
+			return texttemplate.New(name).ParseFiles(p)
+		}
+	}
+	return texttemplate.New(name).Parse(fallback)
+}
+
+// writeServicePage renders d through the configured Renderer into
+// ./doc/<id><ext>. generateHTMLRefPage calls it in place of its old
+// HTML-only body.
+func writeServicePage(d *DirectiveData) error {
+	path := "./doc"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		os.Mkdir(path, os.ModeDir|0777)
+	}
+	r := currentRenderer()
+	ext := renderExt[*formatFlag]
+	if ext == "" {
+		ext = ".html"
+	}
+	d.Filename = d.ID + ext
+
+	f, err := os.Create(path + "/" + d.Filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.RenderService(d, f)
+}
+
+// writeIndexPage renders the table of contents through the configured
+// Renderer into ./doc/INDEX<ext> (or docs.html, for backward compatibility
+// with the HTML renderer's existing filename).
+func writeIndexPage(idx *IndexDataT) error {
+	r := currentRenderer()
+	ext, known := renderExt[*formatFlag]
+	name := "INDEX" + ext
+	if *formatFlag == "html" || !known {
+		name = "docs.html"
+	}
+	f, err := os.Create("./doc/" + name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.RenderIndex(idx, f)
+}
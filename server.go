@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// httpAddr, when non-empty, puts wsdoc into live server mode instead of the
+// default "walk once, write static files, exit" behavior. It mirrors
+// godoc's -http flag, e.g. -http=:6060.
+var httpAddr = flag.String("http", "", "serve docs over HTTP at this address (e.g. :6060) instead of writing static files")
+
+// Corpus holds everything the HTTP server needs to render pages on demand.
+// It is rebuilt from scratch each time the source tree changes, and is the
+// live-mode analogue of the IndexData/doc-file pair that the static path
+// writes to disk.
+type Corpus struct {
+	mu      sync.RWMutex
+	root    string
+	toc     DirDataSlice
+	byID    map[string]*DirectiveData
+	index   *searchIndex
+	date    string // generation date shown on /pkg/ and /index, refreshed on each reload
+	version string
+}
+
+// searchIndex is a tiny full-text index over the Title/Synopsis/Description
+// and URL parts of every DirectiveData, built fresh alongside the Corpus.
+type searchIndex struct {
+	// postings maps a lower-cased word to the set of service IDs whose
+	// text contains that word.
+	postings map[string]map[string]bool
+}
+
+func newSearchIndex(toc DirDataSlice) *searchIndex {
+	si := &searchIndex{postings: make(map[string]map[string]bool)}
+	add := func(id, text string) {
+		for _, w := range strings.Fields(strings.ToLower(text)) {
+			w = strings.Trim(w, ".,;:!?()[]{}\"'")
+			if w == "" {
+				continue
+			}
+			if si.postings[w] == nil {
+				si.postings[w] = make(map[string]bool)
+			}
+			si.postings[w][id] = true
+		}
+	}
+	for i := range toc {
+		d := &toc[i]
+		add(d.ID, d.Title)
+		add(d.ID, d.Synopsis)
+		add(d.ID, d.DescriptionText)
+		for _, u := range d.URLs {
+			add(d.ID, u.URL)
+			for _, p := range u.Parts {
+				add(d.ID, p.Term)
+			}
+		}
+	}
+	return si
+}
+
+// search returns the IDs of services matching every word in q (AND
+// semantics), sorted by ID. A query with no words matches nothing.
+func (si *searchIndex) search(q string) []string {
+	words := strings.Fields(strings.ToLower(q))
+	if len(words) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, w := range words {
+		for id := range si.postings[w] {
+			counts[id]++
+		}
+	}
+	var ids []string
+	for id, n := range counts {
+		if n == len(words) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// newCorpus walks root, runs the directive parser, and returns a Corpus
+// snapshot ready to be served. Live mode renders DirectiveData straight
+// from this in-memory snapshot, so skipStaticWrite is set for the
+// duration of the walk to keep processWebDocLines from also writing the
+// ./doc tree that only the static "walk once, write files, exit" path
+// needs.
+func newCorpus(root string) (*Corpus, error) {
+	IndexData.TOC = nil
+	skipStaticWrite = true
+	err := filepath.Walk(root, processGoFiles)
+	skipStaticWrite = false
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(IndexData.TOC)
+	c := &Corpus{
+		root: root,
+		toc:  IndexData.TOC,
+		byID: make(map[string]*DirectiveData),
+	}
+	c.date, c.version = currentDateVersion()
+	for i := range c.toc {
+		c.byID[c.toc[i].ID] = &c.toc[i]
+	}
+	c.index = newSearchIndex(c.toc)
+	return c, nil
+}
+
+// reload re-walks the source tree and atomically swaps the Corpus's
+// contents, so handlers already in flight keep serving the old snapshot.
+func (c *Corpus) reload() error {
+	nc, err := newCorpus(c.root)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.toc, c.byID, c.index = nc.toc, nc.byID, nc.index
+	c.date, c.version = nc.date, nc.version
+	c.mu.Unlock()
+	return nil
+}
+
+// watch runs until the program exits, re-running reload() whenever a .go
+// file under the corpus root is written, created, or removed.
+func (c *Corpus) watch() {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Printf("Error creating watcher: %s\n", err.Error())
+		return
+	}
+	defer w.Close()
+
+	_ = filepath.Walk(c.root, func(path string, f os.FileInfo, err error) error {
+		if err == nil && f.IsDir() {
+			w.Add(path)
+		}
+		return nil
+	})
+
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if strings.HasSuffix(ev.Name, ".go") {
+				if err := c.reload(); err != nil {
+					fmt.Printf("Error reloading corpus: %s\n", err.Error())
+				}
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			fmt.Printf("Watcher error: %s\n", err.Error())
+		}
+	}
+}
+
+// registerHandlers wires up the live-mode HTTP handlers and is modeled on
+// godoc's handlerServer: each handler renders straight from the Corpus
+// rather than from files written to ./doc.
+func (c *Corpus) registerHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/pkg/", c.handlePkg)
+	mux.HandleFunc("/ws/", c.handleWS)
+	mux.HandleFunc("/search", c.handleSearch)
+	mux.HandleFunc("/index", c.handleIndex)
+	mux.HandleFunc("/api/ws", c.handleAPIWS)
+	mux.HandleFunc("/glossary.html", handleGlossary)
+	// The relative glossary.html#term links on /ws/<id> pages resolve here.
+	mux.HandleFunc("/ws/glossary.html", handleGlossary)
+}
+
+// renderMode is the parsed form of the ?m= query param, analogous to
+// godoc's ?m=src / ?m=all.
+type renderMode struct {
+	src bool // show the Go source file the wsdoc block came from instead of the doc page
+	all bool // recurse into unexported fields via WSTypeFactory
+}
+
+func parseRenderMode(r *http.Request) renderMode {
+	m := r.URL.Query().Get("m")
+	return renderMode{
+		src: strings.Contains(m, "src"),
+		all: strings.Contains(m, "all"),
+	}
+}
+
+// handlePkg lists every documented web service through the docs.html
+// template, in the Corpus's title order, similar to godoc's /pkg/ browsing
+// index. Services are not grouped by package directory.
+func (c *Corpus) handlePkg(w http.ResponseWriter, r *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	t, err := template.New("docs.html").ParseFiles(templatePath("docs.html"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		TOC     DirDataSlice
+		Date    string
+		Version string
+	}{c.toc, c.date, c.version}
+	if err := t.Execute(w, &data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleWS renders a single web service's documentation, honoring ?m=src
+// and ?m=all the same way godoc honors them for a package.
+func (c *Corpus) handleWS(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/ws/")
+	c.mu.RLock()
+	d, ok := c.byID[id]
+	c.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	mode := parseRenderMode(r)
+	if mode.src {
+		c.writeSource(w, d)
+		return
+	}
+	if mode.all {
+		d = withAllFields(d)
+	}
+
+	t, err := template.New("doc.html").ParseFiles(templatePath("doc.html"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := t.Execute(w, d); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// withAllFields re-resolves the Input/Response field lists from their
+// original @input/@response type tokens with includeUnexported set, so
+// ?m=all requests show unexported struct fields that the default
+// (exported-only) rendering filters out.
+func withAllFields(d *DirectiveData) *DirectiveData {
+	cp := *d
+	tmp := &Directive{D: &cp}
+	if cp.InputType != "" {
+		cp.Input = getStructDef(cp.InputType, tmp, true)
+	}
+	if cp.ResponseType != "" {
+		cp.Response = getStructDef(cp.ResponseType, tmp, true)
+	}
+	return &cp
+}
+
+// handleGlossary serves the canonical glossary page that every
+// <span class="glossary"> link points at. It isn't corpus-specific - the
+// glossary is loaded once at startup, not re-walked with the source tree.
+func handleGlossary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := renderGlossaryPage(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeSource serves, for ?m=src requests, the whole raw Go source file
+// that holds the wsdoc block that produced d, after a "// file:line" header
+// naming the block's "wsdoc {" line. It does not cut the file down to the
+// block or its function.
+func (c *Corpus) writeSource(w http.ResponseWriter, d *DirectiveData) {
+	if d.SrcFile == "" {
+		http.Error(w, "source location unknown for this service", http.StatusNotFound)
+		return
+	}
+	b, err := os.ReadFile(d.SrcFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "// %s:%d\n\n", d.SrcFile, d.SrcLine)
+	w.Write(b)
+}
+
+// searchResultsTemplate is parsed with html/template so that the query and
+// every result field are HTML-escaped automatically; q, d.Title and
+// d.Synopsis all come from request input or source comments and must
+// never be interpolated unescaped into the response.
+const searchResultsTemplate = `<html><body><h1>Search results for {{.Query}}</h1><ul>
+{{range .Results}}<li><a href="/ws/{{.ID}}">{{.Title}}</a> - {{.Synopsis}}</li>
+{{end}}</ul></body></html>`
+
+// handleSearch answers /search?q=... against the full-text index, showing
+// results as an HTML list.
+func (c *Corpus) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	c.mu.RLock()
+	ids := c.index.search(q)
+	var results DirDataSlice
+	for _, id := range ids {
+		results = append(results, *c.byID[id])
+	}
+	c.mu.RUnlock()
+
+	t, err := template.New("search").Parse(searchResultsTemplate)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := struct {
+		Query   string
+		Results DirDataSlice
+	}{q, results}
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleIndex serves the same table-of-contents page as /pkg/, kept as a
+// separate route to match godoc's /index naming.
+func (c *Corpus) handleIndex(w http.ResponseWriter, r *http.Request) {
+	c.handlePkg(w, r)
+}
+
+// handleAPIWS serves the raw DirectiveData for a single service as JSON,
+// e.g. GET /api/ws?id=getrentableinfo.
+func (c *Corpus) handleAPIWS(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	c.mu.RLock()
+	d, ok := c.byID[id]
+	c.mu.RUnlock()
+	if !ok {
+		http.Error(w, "unknown id: "+strconv.Quote(id), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+// runServer builds the initial Corpus, starts the source-tree watcher, and
+// blocks serving HTTP until the process is killed.
+func runServer(addr, root string) error {
+	c, err := newCorpus(root)
+	if err != nil {
+		return err
+	}
+	go c.watch()
+
+	mux := http.NewServeMux()
+	c.registerHandlers(mux)
+	if *serveSwaggerFlag {
+		c.registerSwaggerHandlers(mux)
+	}
+	log.Printf("wsdoc serving %s on %s\n", root, addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchIndexSearchRequiresAllWords(t *testing.T) {
+	toc := DirDataSlice{
+		{ID: "getfoo", Title: "Get Foo", Synopsis: "alpha beta"},
+		{ID: "getbar", Title: "Get Bar", Synopsis: "alpha only"},
+	}
+	si := newSearchIndex(toc)
+
+	got := si.search("alpha beta")
+	if len(got) != 1 || got[0] != "getfoo" {
+		t.Errorf("search(%q) = %v, want [getfoo]", "alpha beta", got)
+	}
+
+	got = si.search("alpha")
+	if len(got) != 2 {
+		t.Errorf("search(%q) = %v, want both docs", "alpha", got)
+	}
+}
+
+func TestSearchIndexIndexesPlainDescription(t *testing.T) {
+	toc := DirDataSlice{{
+		ID:              "getfoo",
+		Title:           "Get Foo",
+		Description:     `the <span class="glossary"><a href="glossary.html#bui">BUI</a></span> field`,
+		DescriptionText: "the BUI field",
+	}}
+	si := newSearchIndex(toc)
+
+	got := si.search("bui")
+	if len(got) != 1 || got[0] != "getfoo" {
+		t.Errorf("search(%q) = %v, want [getfoo]", "bui", got)
+	}
+	if _, found := si.postings[`class="glossary"><a`]; found {
+		t.Error("postings contain raw HTML markup from Description; should index DescriptionText instead")
+	}
+}
+
+func TestSearchIndexSearchNoMatch(t *testing.T) {
+	toc := DirDataSlice{{ID: "getfoo", Title: "Get Foo", Synopsis: "alpha"}}
+	si := newSearchIndex(toc)
+
+	if got := si.search("alpha gamma"); len(got) != 0 {
+		t.Errorf("search(%q) = %v, want no results", "alpha gamma", got)
+	}
+	if got := si.search(""); len(got) != 0 {
+		t.Errorf("search(\"\") = %v, want no results", got)
+	}
+}
+
+func TestParseRenderMode(t *testing.T) {
+	cases := []struct {
+		query string
+		want  renderMode
+	}{
+		{"", renderMode{}},
+		{"m=src", renderMode{src: true}},
+		{"m=all", renderMode{all: true}},
+		{"m=src,all", renderMode{src: true, all: true}},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/ws/getfoo?"+c.query, nil)
+		if got := parseRenderMode(r); got != c.want {
+			t.Errorf("parseRenderMode(%q) = %+v, want %+v", c.query, got, c.want)
+		}
+	}
+}
+
+func newTestCorpus() *Corpus {
+	toc := DirDataSlice{
+		{ID: "getfoo", Title: "Get Foo", Synopsis: "returns a foo"},
+	}
+	c := &Corpus{
+		toc:  toc,
+		byID: map[string]*DirectiveData{"getfoo": &toc[0]},
+	}
+	c.index = newSearchIndex(c.toc)
+	return c
+}
+
+func TestHandleAPIWS(t *testing.T) {
+	c := newTestCorpus()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/ws?id=getfoo", nil)
+	w := httptest.NewRecorder()
+	c.handleAPIWS(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), `"Title":"Get Foo"`) {
+		t.Errorf("body = %s, want it to contain the service title", w.Body.String())
+	}
+}
+
+func TestHandleAPIWSUnknownID(t *testing.T) {
+	c := newTestCorpus()
+
+	r := httptest.NewRequest(http.MethodGet, "/api/ws?id=nosuchid", nil)
+	w := httptest.NewRecorder()
+	c.handleAPIWS(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleSearch(t *testing.T) {
+	c := newTestCorpus()
+
+	r := httptest.NewRequest(http.MethodGet, "/search?q=foo", nil)
+	w := httptest.NewRecorder()
+	c.handleSearch(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !strings.Contains(w.Body.String(), "/ws/getfoo") {
+		t.Errorf("body = %s, want a link to /ws/getfoo", w.Body.String())
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// strictFlag causes the tool to exit non-zero when the cross-reference
+// report finds undefined terms or duplicate @title IDs, so CI can enforce
+// documentation completeness.
+var strictFlag = flag.Bool("strict", false, "exit non-zero if the cross-reference report finds undefined glossary terms or duplicate titles")
+
+// xrefLocation is where a term was first referenced from, so the report
+// can point at the wsdoc block responsible instead of just naming the term.
+type xrefLocation struct {
+	File string
+	Line int
+}
+
+// xrefTerms accumulates every term looked up via definitionLookup (URL
+// parts and struct field names), keyed by lower-cased term, to the first
+// source location that referenced it.
+var xrefTerms = make(map[string]xrefLocation)
+
+// registeredGlossaryTerms collects every term LoadGlossary has loaded, via
+// RegisterGlossaryTerm, keyed by lower-cased term. Kept separately from
+// xrefTerms so the report can tell "referenced but undefined" apart from
+// "defined but never referenced".
+var registeredGlossaryTerms = make(map[string]bool)
+
+// RegisterGlossaryTerm records that term was loaded into the glossary.
+// Called from registerGlossaryTermsFromCSV for every term it reads that
+// isKnownGlossaryTerm recognizes, so the
+// cross-reference report can flag glossary entries that no web service
+// ever references.
+func RegisterGlossaryTerm(term string) {
+	registeredGlossaryTerms[strings.ToLower(term)] = true
+}
+
+// isKnownGlossaryTerm reports whether term is one LoadGlossary actually
+// loaded. It defaults to IsGlossaryTerm - the same predicate LoadGlossary's
+// own callers (getDefinition et al.) use to recognize a loaded term - and
+// is a package var purely so tests can substitute a fixture without a real
+// glossary CSV.
+var isKnownGlossaryTerm = IsGlossaryTerm
+
+// registerGlossaryTermsFromCSV reads path - the same glossary CSV files
+// LoadGlossary loads for getDefinition/IsGlossaryTerm - and calls
+// RegisterGlossaryTerm for every row whose first column isKnownGlossaryTerm
+// recognizes. LoadGlossary itself lives outside this tree and has no hook
+// for registration, so main() runs this alongside it on the same files;
+// checking isKnownGlossaryTerm rather than just "column 0 is non-empty"
+// keeps registration in lockstep with whatever LoadGlossary actually
+// loaded, instead of re-guessing its row format (e.g. a header row whose
+// first column isn't a real term would fail isKnownGlossaryTerm and be
+// skipped).
+func registerGlossaryTermsFromCSV(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(rec) > 0 && rec[0] != "" && isKnownGlossaryTerm(rec[0]) {
+			RegisterGlossaryTerm(rec[0])
+		}
+	}
+}
+
+// definitionLookup wraps getDefinition, recording the source location
+// (currentSrcFile/currentSrcLine, set by processWebDocLines) of the first
+// wsdoc block that referenced term, so the report can say "undefined
+// glossary term at foo.go:123" instead of just naming the term. Should be
+// used at every call site that used to call getDefinition directly.
+// ListVars passes its indented display name ("&nbsp;..." plus "Type." for
+// nested fields); only the bare field name is used as the key.
+func definitionLookup(term string) template.HTML {
+	key := strings.TrimSpace(strings.ReplaceAll(term, "&nbsp;", ""))
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.ToLower(key)
+	if _, seen := xrefTerms[key]; !seen {
+		xrefTerms[key] = xrefLocation{File: currentSrcFile, Line: currentSrcLine}
+	}
+	return getDefinition(term)
+}
+
+// IncompleteEntry names a web service missing one or more of the fields
+// CI should require before merge.
+type IncompleteEntry struct {
+	Title   string   `json:"title"`
+	Missing []string `json:"missing"`
+}
+
+// UndefinedTerm names a URL part or struct field that was referenced but
+// has no glossary definition, along with the wsdoc block that referenced
+// it, e.g. "undefined glossary term at foo.go:123".
+type UndefinedTerm struct {
+	Term string `json:"term"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// XrefReport is the machine-readable form of report.json: a
+// documentation-completeness snapshot gathered after every file has been
+// parsed.
+type XrefReport struct {
+	UndefinedTerms  []UndefinedTerm   `json:"undefinedTerms"`      // URL parts/fields referenced but never defined in the glossary
+	UnusedGlossary  []string          `json:"unusedGlossaryTerms"` // glossary entries no web service references
+	IncompleteWS    []IncompleteEntry `json:"incompleteServices"`  // missing @synopsis/@description/@response
+	DuplicateTitles []string          `json:"duplicateTitles"`     // Filenames that more than one @title maps to
+}
+
+// buildXrefReport walks toc plus the accumulated xrefTerms/
+// registeredGlossaryTerms maps and produces the four categories described
+// in the cross-reference report.
+func buildXrefReport(toc DirDataSlice) *XrefReport {
+	rep := &XrefReport{}
+
+	for term, loc := range xrefTerms {
+		if !IsGlossaryTerm(term) {
+			rep.UndefinedTerms = append(rep.UndefinedTerms, UndefinedTerm{Term: term, File: loc.File, Line: loc.Line})
+		}
+	}
+	sort.Slice(rep.UndefinedTerms, func(i, j int) bool { return rep.UndefinedTerms[i].Term < rep.UndefinedTerms[j].Term })
+
+	for term := range registeredGlossaryTerms {
+		if _, referenced := xrefTerms[term]; !referenced {
+			rep.UnusedGlossary = append(rep.UnusedGlossary, term)
+		}
+	}
+	sort.Strings(rep.UnusedGlossary)
+
+	seen := make(map[string]int)
+	for i := range toc {
+		d := &toc[i]
+		seen[d.Filename]++
+
+		var missing []string
+		if d.Synopsis == "" {
+			missing = append(missing, "@synopsis")
+		}
+		if len(d.Description) == 0 {
+			missing = append(missing, "@description")
+		}
+		if len(d.Response) == 0 {
+			missing = append(missing, "@response")
+		}
+		if len(missing) > 0 {
+			rep.IncompleteWS = append(rep.IncompleteWS, IncompleteEntry{Title: d.Title, Missing: missing})
+		}
+	}
+
+	for filename, count := range seen {
+		if count > 1 {
+			rep.DuplicateTitles = append(rep.DuplicateTitles, filename)
+		}
+	}
+	sort.Strings(rep.DuplicateTitles)
+
+	return rep
+}
+
+const reportHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head><title>wsdoc coverage report</title></head>
+<body>
+<h1>Documentation coverage report</h1>
+
+<h2>Undefined glossary terms</h2>
+<ul>{{range .UndefinedTerms}}<li>{{.Term}} ({{.File}}:{{.Line}})</li>{{end}}</ul>
+
+<h2>Unused glossary terms</h2>
+<ul>{{range .UnusedGlossary}}<li>{{.}}</li>{{end}}</ul>
+
+<h2>Incomplete web services</h2>
+<ul>{{range .IncompleteWS}}<li>{{.Title}}: missing {{range .Missing}}{{.}} {{end}}</li>{{end}}</ul>
+
+<h2>Duplicate titles</h2>
+<ul>{{range .DuplicateTitles}}<li>{{.}}</li>{{end}}</ul>
+</body>
+</html>
+`
+
+// generateXrefReport writes report.html and report.json for toc, and
+// returns the report so main() can honor -strict. It always builds and
+// returns the report - even when skipStaticWrite is set, e.g. under
+// -openapi, so -strict still gates on it - but only writes the ./doc
+// files when that backend actually wants a ./doc tree.
+func generateXrefReport(toc DirDataSlice) (*XrefReport, error) {
+	rep := buildXrefReport(toc)
+	if skipStaticWrite {
+		return rep, nil
+	}
+
+	path := "./doc"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		os.Mkdir(path, os.ModeDir|0777)
+	}
+
+	f, err := os.Create(path + "/report.html")
+	if err != nil {
+		return rep, err
+	}
+	defer f.Close()
+	t, err := template.New("report.html").Parse(reportHTMLTemplate)
+	if err != nil {
+		return rep, err
+	}
+	if err := t.Execute(f, rep); err != nil {
+		return rep, err
+	}
+
+	j, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return rep, err
+	}
+	return rep, os.WriteFile(path+"/report.json", j, 0644)
+}
+
+// reportIsClean reports whether rep has no findings in the categories
+// -strict treats as failures: undefined terms and duplicate titles.
+func reportIsClean(rep *XrefReport) bool {
+	return len(rep.UndefinedTerms) == 0 && len(rep.DuplicateTitles) == 0
+}
+
+// printReportSummary prints one line with the count of each category on
+// stdout, followed by one line per undefined term, independent of the
+// HTML/JSON files.
+func printReportSummary(rep *XrefReport) {
+	fmt.Printf("wsdoc coverage: %d undefined term(s), %d unused glossary term(s), %d incomplete service(s), %d duplicate title(s)\n",
+		len(rep.UndefinedTerms), len(rep.UnusedGlossary), len(rep.IncompleteWS), len(rep.DuplicateTitles))
+	for _, t := range rep.UndefinedTerms {
+		fmt.Printf("  undefined glossary term %q at %s:%d\n", t.Term, t.File, t.Line)
+	}
+}
+
+// glossaryEntry is one row of the canonical glossary page: the term and
+// its definition, as looked up through getDefinition.
+type glossaryEntry struct {
+	Term       string
+	Definition template.HTML
+}
+
+// glossaryEntries returns every term registered via RegisterGlossaryTerm,
+// sorted, with its definition resolved - the data backing the
+// glossary.html#term anchors that the glossary spans link to.
+func glossaryEntries() []glossaryEntry {
+	terms := make([]string, 0, len(registeredGlossaryTerms))
+	for t := range registeredGlossaryTerms {
+		terms = append(terms, t)
+	}
+	sort.Strings(terms)
+
+	entries := make([]glossaryEntry, 0, len(terms))
+	for _, t := range terms {
+		entries = append(entries, glossaryEntry{Term: t, Definition: getDefinition(t)})
+	}
+	return entries
+}
+
+const glossaryHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head><title>Glossary</title></head>
+<body>
+<h1>Glossary</h1>
+<dl>
+{{range .}}<dt id="{{.Term}}">{{.Term}}</dt><dd>{{.Definition}}</dd>
+{{end}}</dl>
+</body>
+</html>
+`
+
+// renderGlossaryPage writes the canonical glossary page to w - the target
+// of every glossary.html#term link produced by renderGlossaryHTML and
+// renderGlossaryMD.
+func renderGlossaryPage(w io.Writer) error {
+	t, err := template.New("glossary.html").Parse(glossaryHTMLTemplate)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, glossaryEntries())
+}
+
+// generateGlossaryPage writes ./doc/glossary.html for the static (non
+// -http) output path.
+func generateGlossaryPage() error {
+	path := "./doc"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		os.Mkdir(path, os.ModeDir|0777)
+	}
+	f, err := os.Create(path + "/glossary.html")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return renderGlossaryPage(f)
+}
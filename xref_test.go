@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withCleanXrefState resets the package-level xref maps around a test so
+// tests don't interfere with each other or depend on whatever a prior test
+// (or package init) left behind.
+func withCleanXrefState(t *testing.T) {
+	t.Helper()
+	savedXrefTerms := xrefTerms
+	savedGlossaryTerms := registeredGlossaryTerms
+	xrefTerms = make(map[string]xrefLocation)
+	registeredGlossaryTerms = make(map[string]bool)
+	t.Cleanup(func() {
+		xrefTerms = savedXrefTerms
+		registeredGlossaryTerms = savedGlossaryTerms
+	})
+}
+
+func TestRegisterGlossaryTermsFromCSVSkipsHeaderAndUnknownRows(t *testing.T) {
+	withCleanXrefState(t)
+
+	savedKnown := isKnownGlossaryTerm
+	known := map[string]bool{"bui": true, "raid": true}
+	isKnownGlossaryTerm = func(term string) bool { return known[term] }
+	t.Cleanup(func() { isKnownGlossaryTerm = savedKnown })
+
+	path := filepath.Join(t.TempDir(), "glossary.csv")
+	csv := "term,definition\nbui,Business Unit ID\nraid,Rentable Asset ID\nbogus,not a real term\n"
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if err := registerGlossaryTermsFromCSV(path); err != nil {
+		t.Fatalf("registerGlossaryTermsFromCSV: %v", err)
+	}
+
+	if !registeredGlossaryTerms["bui"] || !registeredGlossaryTerms["raid"] {
+		t.Errorf("registeredGlossaryTerms = %v, want bui and raid registered", registeredGlossaryTerms)
+	}
+	if registeredGlossaryTerms["term"] {
+		t.Error("header row's first column was registered as a glossary term")
+	}
+	if registeredGlossaryTerms["bogus"] {
+		t.Error("a row isKnownGlossaryTerm rejects was registered anyway")
+	}
+}
+
+func TestBuildXrefReportIncompleteWS(t *testing.T) {
+	withCleanXrefState(t)
+
+	toc := DirDataSlice{
+		{Title: "complete", Filename: "complete.html", Synopsis: "a synopsis", Description: "a description", Response: []ProtocolJSON{{Field: "X"}}},
+		{Title: "incomplete", Filename: "incomplete.html"},
+	}
+	rep := buildXrefReport(toc)
+
+	if len(rep.IncompleteWS) != 1 {
+		t.Fatalf("got %d incomplete entries, want 1", len(rep.IncompleteWS))
+	}
+	entry := rep.IncompleteWS[0]
+	if entry.Title != "incomplete" {
+		t.Errorf("incomplete entry title = %q, want %q", entry.Title, "incomplete")
+	}
+	want := []string{"@synopsis", "@description", "@response"}
+	if len(entry.Missing) != len(want) {
+		t.Fatalf("missing = %v, want %v", entry.Missing, want)
+	}
+	for i, w := range want {
+		if entry.Missing[i] != w {
+			t.Errorf("missing[%d] = %q, want %q", i, entry.Missing[i], w)
+		}
+	}
+}
+
+func TestBuildXrefReportDuplicateTitles(t *testing.T) {
+	withCleanXrefState(t)
+
+	toc := DirDataSlice{
+		{Title: "a", Filename: "dup.html", Synopsis: "s", Description: "d", Response: []ProtocolJSON{{Field: "X"}}},
+		{Title: "b", Filename: "dup.html", Synopsis: "s", Description: "d", Response: []ProtocolJSON{{Field: "X"}}},
+		{Title: "c", Filename: "unique.html", Synopsis: "s", Description: "d", Response: []ProtocolJSON{{Field: "X"}}},
+	}
+	rep := buildXrefReport(toc)
+
+	if len(rep.DuplicateTitles) != 1 || rep.DuplicateTitles[0] != "dup.html" {
+		t.Errorf("DuplicateTitles = %v, want [dup.html]", rep.DuplicateTitles)
+	}
+}
+
+func TestBuildXrefReportUnusedGlossary(t *testing.T) {
+	withCleanXrefState(t)
+
+	RegisterGlossaryTerm("BUI")
+	RegisterGlossaryTerm("RAID")
+	xrefTerms["bui"] = xrefLocation{File: "foo.go", Line: 10}
+
+	rep := buildXrefReport(nil)
+
+	if len(rep.UnusedGlossary) != 1 || rep.UnusedGlossary[0] != "raid" {
+		t.Errorf("UnusedGlossary = %v, want [raid]", rep.UnusedGlossary)
+	}
+}
+
+func TestReportIsClean(t *testing.T) {
+	clean := &XrefReport{}
+	if !reportIsClean(clean) {
+		t.Error("expected empty report to be clean")
+	}
+
+	dirty := &XrefReport{UndefinedTerms: []UndefinedTerm{{Term: "foo", File: "foo.go", Line: 1}}}
+	if reportIsClean(dirty) {
+		t.Error("expected report with undefined terms not to be clean")
+	}
+}